@@ -0,0 +1,101 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	vmTraceFlag = &cli.StringFlag{
+		Name:  "vmtrace",
+		Usage: "Name of tracer to run during block import, attached as a live tracer rather than invoked per RPC call",
+	}
+	vmTraceConfigFlag = &cli.StringFlag{
+		Name:  "vmtrace.config",
+		Usage: "JSON configuration passed to the tracer named by --vmtrace",
+	}
+	vmTraceDestFlag = &cli.StringFlag{
+		Name:  "vmtrace.dest",
+		Usage: "Where to stream --vmtrace output: 'stdout', a directory path (one NDJSON file per block), or unix:///path/to.sock",
+		Value: "stdout",
+	}
+)
+
+// LiveTracerFlags are the --vmtrace* flags; app.Flags in main.go appends
+// these alongside the rest of geth's flag groups.
+var LiveTracerFlags = []cli.Flag{vmTraceFlag, vmTraceConfigFlag, vmTraceDestFlag}
+
+// setupLiveTracer builds a tracers.LiveTracer from the --vmtrace* flags, or
+// returns (nil, nil, nil) if --vmtrace was not set.
+func setupLiveTracer(ctx *cli.Context, chainConfig *params.ChainConfig) (*tracers.LiveTracer, tracers.Sink, error) {
+	name := ctx.String(vmTraceFlag.Name)
+	if name == "" {
+		return nil, nil, nil
+	}
+	cfg := json.RawMessage(ctx.String(vmTraceConfigFlag.Name))
+	if len(cfg) == 0 {
+		cfg = json.RawMessage("{}")
+	}
+	sink, err := newLiveTraceSink(ctx.String(vmTraceDestFlag.Name))
+	if err != nil {
+		return nil, nil, err
+	}
+	lt, err := tracers.NewLiveTracer(tracers.LiveConfig{Name: name, Config: cfg}, chainConfig, sink)
+	if err != nil {
+		sink.Close()
+		return nil, nil, fmt.Errorf("invalid --vmtrace %q: %w", name, err)
+	}
+	return lt, sink, nil
+}
+
+// VMConfig builds the vm.Config to pass to core.NewBlockChain, wiring in the
+// live tracer requested via --vmtrace (if any) as its Tracer. The result is
+// for use when constructing the BlockChain — live tracers, unlike the ones in
+// tracers.DefaultDirectory, must be in place before the chain starts
+// importing blocks rather than attached per RPC call. The returned close
+// func closes the configured sink and must be called on node shutdown; it
+// is a no-op when --vmtrace was not set.
+func VMConfig(ctx *cli.Context, chainConfig *params.ChainConfig) (vm.Config, func() error, error) {
+	lt, sink, err := setupLiveTracer(ctx, chainConfig)
+	if err != nil {
+		return vm.Config{}, nil, err
+	}
+	if lt == nil {
+		return vm.Config{}, func() error { return nil }, nil
+	}
+	return vm.Config{Tracer: lt.Hooks()}, sink.Close, nil
+}
+
+func newLiveTraceSink(dest string) (tracers.Sink, error) {
+	switch {
+	case dest == "" || dest == "stdout":
+		return tracers.NewWriterSink(os.Stdout), nil
+	case strings.HasPrefix(dest, "unix://"):
+		return tracers.NewUnixSocketSink(strings.TrimPrefix(dest, "unix://")), nil
+	default:
+		return tracers.NewFileSink(dest), nil
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// txTraceResult is the result of a single transaction trace, returned by
+// debug_traceBlockByNumber, debug_traceBlockByHash and debug_traceChain.
+// Carrying the block number and transaction hash alongside the result lets
+// callers correlate entries without maintaining a parallel index.
+type txTraceResult struct {
+	BlockNumber uint64          `json:"blockNumber,omitempty"` // Block number of the block this transaction was in
+	TxHash      common.Hash     `json:"txHash"`                // Transaction hash of the traced tx
+	Result      json.RawMessage `json:"result,omitempty"`      // Trace results produced by the tracer
+	Error       string          `json:"error,omitempty"`       // Trace failure produced by the tracer
+}
+
+// TraceConfig holds extra parameters to Trace functions.
+type TraceConfig struct {
+	Tracer *string
+	Config json.RawMessage
+}
+
+// Backend is the set of methods the tracing API needs from the node in
+// order to replay blocks.
+type Backend interface {
+	ChainConfig() *params.ChainConfig
+	StateAtBlock(ctx context.Context, block *types.Block) (*state.StateDB, error)
+}
+
+// traceBlock re-executes every transaction in block and returns one
+// txTraceResult per transaction, in transaction order.
+func traceBlock(ctx context.Context, backend Backend, block *types.Block, config *TraceConfig) ([]*txTraceResult, error) {
+	if block.NumberU64() == 0 {
+		return nil, errors.New("genesis is not traceable")
+	}
+	statedb, err := backend.StateAtBlock(ctx, block)
+	if err != nil {
+		return nil, fmt.Errorf("tracing failed: %w", err)
+	}
+	chainConfig := backend.ChainConfig()
+
+	var (
+		txs       = block.Transactions()
+		results   = make([]*txTraceResult, len(txs))
+		blockHash = block.Hash()
+		signer    = types.MakeSigner(chainConfig, block.Number(), block.Time())
+		blockCtx  = core.NewEVMBlockContext(block.Header(), nil, nil)
+	)
+	for i, tx := range txs {
+		msg, err := core.TransactionToMessage(tx, signer, block.BaseFee())
+		if err != nil {
+			results[i] = &txTraceResult{BlockNumber: block.NumberU64(), TxHash: tx.Hash(), Error: err.Error()}
+			continue
+		}
+		txctx := &Context{
+			BlockHash:   blockHash,
+			BlockNumber: block.NumberU64(),
+			TxIndex:     i,
+			TxHash:      tx.Hash(),
+		}
+		tracer, err := DefaultDirectory.New(*config.Tracer, txctx, config.Config, chainConfig)
+		if err != nil {
+			return nil, err
+		}
+		statedb.SetTxContext(tx.Hash(), i)
+
+		txCtx := core.NewEVMTxContext(msg)
+		evm := vm.NewEVM(blockCtx, txCtx, statedb, chainConfig, vm.Config{Tracer: tracer.Hooks})
+		if _, err := core.ApplyMessage(evm, msg, new(core.GasPool).AddGas(msg.GasLimit)); err != nil {
+			results[i] = &txTraceResult{BlockNumber: block.NumberU64(), TxHash: tx.Hash(), Error: err.Error()}
+			continue
+		}
+		res, err := tracer.GetResult()
+		if err != nil {
+			results[i] = &txTraceResult{BlockNumber: block.NumberU64(), TxHash: tx.Hash(), Error: err.Error()}
+			continue
+		}
+		results[i] = &txTraceResult{BlockNumber: block.NumberU64(), TxHash: tx.Hash(), Result: res}
+	}
+	return results, nil
+}
+
+// traceChain runs traceBlock across every block in blocks, in order,
+// returning one []*txTraceResult per block.
+func traceChain(ctx context.Context, backend Backend, blocks []*types.Block, config *TraceConfig) ([][]*txTraceResult, error) {
+	out := make([][]*txTraceResult, len(blocks))
+	for i, block := range blocks {
+		res, err := traceBlock(ctx, backend, block, config)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = res
+	}
+	return out, nil
+}
@@ -0,0 +1,234 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"encoding/json"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// LiveDirectory is the collection of tracers that can be attached directly
+// to the blockchain at node startup, as opposed to the tracers in
+// DefaultDirectory which are instantiated on demand for a single RPC call.
+var LiveDirectory = directory{elems: make(map[string]elem)}
+
+// FrameResult is the payload streamed to a live tracer's sink once per
+// traced transaction.
+type FrameResult struct {
+	BlockNumber uint64          `json:"blockNumber"`
+	BlockHash   common.Hash     `json:"blockHash"`
+	TxHash      common.Hash     `json:"txHash"`
+	TxIndex     int             `json:"txIndex"`
+	Result      json.RawMessage `json:"result"`
+}
+
+// Sink receives one FrameResult per transaction traced by a live tracer.
+type Sink interface {
+	Write(FrameResult) error
+	Close() error
+}
+
+// LiveConfig bundles the parameters needed to attach a registered tracer to
+// the chain as a live tracer.
+type LiveConfig struct {
+	Name   string          // Name the tracer is registered under in LiveDirectory
+	Config json.RawMessage // Tracer-specific configuration, as passed via --vmtrace.config
+}
+
+// LiveTracer wraps a Tracer instantiated from LiveDirectory together with
+// the sink its per-transaction results are streamed to. It re-creates the
+// underlying tracer for every transaction (most native tracers are stateful
+// accumulators scoped to a single execution) while presenting a single
+// long-lived tracing.Hooks value to the blockchain.
+type LiveTracer struct {
+	name        string
+	cfg         json.RawMessage
+	chainConfig *params.ChainConfig
+	sink        Sink
+
+	cur         *Tracer
+	blockNumber uint64
+	blockHash   common.Hash
+	txIndex     int
+	txHash      common.Hash
+}
+
+// NewLiveTracer looks up name in LiveDirectory and wraps it for attachment
+// to a blockchain via Hooks.
+func NewLiveTracer(cfg LiveConfig, chainConfig *params.ChainConfig, sink Sink) (*LiveTracer, error) {
+	lt := &LiveTracer{
+		name:        cfg.Name,
+		cfg:         cfg.Config,
+		chainConfig: chainConfig,
+		sink:        sink,
+	}
+	if !LiveDirectory.Lookup(cfg.Name) {
+		return nil, errUnknownLiveTracer(cfg.Name)
+	}
+	// Build one throwaway instance so that an invalid config is reported
+	// here rather than on every transaction.
+	if _, err := LiveDirectory.New(cfg.Name, nil, cfg.Config, chainConfig); err != nil {
+		return nil, err
+	}
+	return lt, nil
+}
+
+// Hooks returns the tracing.Hooks to install on the blockchain's vm.Config.
+// Besides the block/tx bookkeeping hooks it needs for itself, it proxies
+// every other hook through to whichever tracer instance is currently active
+// for the transaction being executed (lt.cur) — that instance is what
+// actually populates the result OnTxEnd streams to the sink.
+func (lt *LiveTracer) Hooks() *tracing.Hooks {
+	return &tracing.Hooks{
+		OnBlockStart:    lt.onBlockStart,
+		OnTxStart:       lt.onTxStart,
+		OnTxEnd:         lt.onTxEnd,
+		OnEnter:         lt.onEnter,
+		OnExit:          lt.onExit,
+		OnOpcode:        lt.onOpcode,
+		OnFault:         lt.onFault,
+		OnGasChange:     lt.onGasChange,
+		OnBalanceChange: lt.onBalanceChange,
+		OnNonceChange:   lt.onNonceChange,
+		OnCodeChange:    lt.onCodeChange,
+		OnStorageChange: lt.onStorageChange,
+		OnLog:           lt.onLog,
+	}
+}
+
+func (lt *LiveTracer) onBlockStart(ev tracing.BlockEvent) {
+	lt.blockNumber = ev.Block.NumberU64()
+	lt.blockHash = ev.Block.Hash()
+	lt.txIndex = 0
+}
+
+func (lt *LiveTracer) onTxStart(env *tracing.VMContext, tx *types.Transaction, from common.Address) {
+	t, err := LiveDirectory.New(lt.name, &Context{
+		BlockHash:   lt.blockHash,
+		BlockNumber: lt.blockNumber,
+		TxIndex:     lt.txIndex,
+		TxHash:      tx.Hash(),
+	}, lt.cfg, lt.chainConfig)
+	if err != nil {
+		// The tracer was validated at construction time; a failure here
+		// would indicate a bug in its constructor.
+		return
+	}
+	lt.cur = t
+	lt.txHash = tx.Hash()
+	if lt.cur.OnTxStart != nil {
+		lt.cur.OnTxStart(env, tx, from)
+	}
+}
+
+func (lt *LiveTracer) onTxEnd(receipt *types.Receipt, err error) {
+	if lt.cur == nil {
+		return
+	}
+	if lt.cur.OnTxEnd != nil {
+		lt.cur.OnTxEnd(receipt, err)
+	}
+	// receipt is nil when the transaction failed before producing one; fall
+	// back to the tx hash captured in onTxStart rather than dereferencing it.
+	txHash := lt.txHash
+	if receipt != nil {
+		txHash = receipt.TxHash
+	}
+	res, resErr := lt.cur.GetResult()
+	if resErr == nil {
+		lt.sink.Write(FrameResult{
+			BlockNumber: lt.blockNumber,
+			BlockHash:   lt.blockHash,
+			TxHash:      txHash,
+			TxIndex:     lt.txIndex,
+			Result:      res,
+		})
+	}
+	lt.txIndex++
+	lt.cur = nil
+}
+
+func (lt *LiveTracer) onEnter(depth int, typ byte, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	if lt.cur != nil && lt.cur.OnEnter != nil {
+		lt.cur.OnEnter(depth, typ, from, to, input, gas, value)
+	}
+}
+
+func (lt *LiveTracer) onExit(depth int, output []byte, gasUsed uint64, err error, reverted bool) {
+	if lt.cur != nil && lt.cur.OnExit != nil {
+		lt.cur.OnExit(depth, output, gasUsed, err, reverted)
+	}
+}
+
+func (lt *LiveTracer) onOpcode(pc uint64, op byte, gas, cost uint64, scope tracing.OpContext, rData []byte, depth int, err error) {
+	if lt.cur != nil && lt.cur.OnOpcode != nil {
+		lt.cur.OnOpcode(pc, op, gas, cost, scope, rData, depth, err)
+	}
+}
+
+func (lt *LiveTracer) onFault(pc uint64, op byte, gas, cost uint64, scope tracing.OpContext, depth int, err error) {
+	if lt.cur != nil && lt.cur.OnFault != nil {
+		lt.cur.OnFault(pc, op, gas, cost, scope, depth, err)
+	}
+}
+
+func (lt *LiveTracer) onGasChange(old, new uint64, reason tracing.GasChangeReason) {
+	if lt.cur != nil && lt.cur.OnGasChange != nil {
+		lt.cur.OnGasChange(old, new, reason)
+	}
+}
+
+func (lt *LiveTracer) onBalanceChange(addr common.Address, prev, new *big.Int, reason tracing.BalanceChangeReason) {
+	if lt.cur != nil && lt.cur.OnBalanceChange != nil {
+		lt.cur.OnBalanceChange(addr, prev, new, reason)
+	}
+}
+
+func (lt *LiveTracer) onNonceChange(addr common.Address, prev, new uint64) {
+	if lt.cur != nil && lt.cur.OnNonceChange != nil {
+		lt.cur.OnNonceChange(addr, prev, new)
+	}
+}
+
+func (lt *LiveTracer) onCodeChange(addr common.Address, prevCodeHash common.Hash, prevCode []byte, codeHash common.Hash, code []byte) {
+	if lt.cur != nil && lt.cur.OnCodeChange != nil {
+		lt.cur.OnCodeChange(addr, prevCodeHash, prevCode, codeHash, code)
+	}
+}
+
+func (lt *LiveTracer) onStorageChange(addr common.Address, slot common.Hash, prev, new common.Hash) {
+	if lt.cur != nil && lt.cur.OnStorageChange != nil {
+		lt.cur.OnStorageChange(addr, slot, prev, new)
+	}
+}
+
+func (lt *LiveTracer) onLog(log *types.Log) {
+	if lt.cur != nil && lt.cur.OnLog != nil {
+		lt.cur.OnLog(log)
+	}
+}
+
+type errUnknownLiveTracer string
+
+func (e errUnknownLiveTracer) Error() string {
+	return "no live tracer named " + string(e)
+}
@@ -0,0 +1,115 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+)
+
+// writerSink streams NDJSON-framed results to an io.Writer, e.g. os.Stdout.
+type writerSink struct {
+	w   io.Writer
+	enc *json.Encoder
+}
+
+// NewWriterSink returns a Sink that writes one NDJSON line per FrameResult
+// to w. It is used for the stdout destination of --vmtrace.
+func NewWriterSink(w io.Writer) Sink {
+	return &writerSink{w: w, enc: json.NewEncoder(w)}
+}
+
+func (s *writerSink) Write(fr FrameResult) error {
+	return s.enc.Encode(fr)
+}
+
+func (s *writerSink) Close() error {
+	if c, ok := s.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// fileSink writes one NDJSON document per traced block to dir, named by
+// block number, rather than appending everything to a single growing file.
+type fileSink struct {
+	dir string
+	cur *os.File
+	num uint64
+}
+
+// NewFileSink returns a Sink that rotates a fresh file per block number
+// under dir.
+func NewFileSink(dir string) Sink {
+	return &fileSink{dir: dir}
+}
+
+func (s *fileSink) Write(fr FrameResult) error {
+	if s.cur == nil || fr.BlockNumber != s.num {
+		if s.cur != nil {
+			s.cur.Close()
+		}
+		f, err := os.Create(filepath.Join(s.dir, fmt.Sprintf("%d.ndjson", fr.BlockNumber)))
+		if err != nil {
+			return err
+		}
+		s.cur, s.num = f, fr.BlockNumber
+	}
+	return json.NewEncoder(s.cur).Encode(fr)
+}
+
+func (s *fileSink) Close() error {
+	if s.cur != nil {
+		return s.cur.Close()
+	}
+	return nil
+}
+
+// socketSink streams NDJSON-framed results over a Unix domain socket
+// connection, reconnecting lazily if the peer is not yet listening.
+type socketSink struct {
+	path string
+	conn net.Conn
+}
+
+// NewUnixSocketSink returns a Sink that dials the unix socket at path and
+// streams NDJSON-framed results over it.
+func NewUnixSocketSink(path string) Sink {
+	return &socketSink{path: path}
+}
+
+func (s *socketSink) Write(fr FrameResult) error {
+	if s.conn == nil {
+		conn, err := net.Dial("unix", s.path)
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+	}
+	return json.NewEncoder(s.conn).Encode(fr)
+}
+
+func (s *socketSink) Close() error {
+	if s.conn != nil {
+		return s.conn.Close()
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// TestLiveTracerProxiesAllHooks verifies that every hook exposed on the
+// per-tx tracer instance (lt.cur) is forwarded by the tracing.Hooks
+// returned from LiveTracer.Hooks, not just the block/tx bookkeeping hooks.
+func TestLiveTracerProxiesAllHooks(t *testing.T) {
+	called := make(map[string]bool)
+	stub := &Tracer{
+		Hooks: &tracing.Hooks{
+			OnEnter:         func(int, byte, common.Address, common.Address, []byte, uint64, *big.Int) { called["OnEnter"] = true },
+			OnExit:          func(int, []byte, uint64, error, bool) { called["OnExit"] = true },
+			OnOpcode:        func(uint64, byte, uint64, uint64, tracing.OpContext, []byte, int, error) { called["OnOpcode"] = true },
+			OnFault:         func(uint64, byte, uint64, uint64, tracing.OpContext, int, error) { called["OnFault"] = true },
+			OnGasChange:     func(uint64, uint64, tracing.GasChangeReason) { called["OnGasChange"] = true },
+			OnBalanceChange: func(common.Address, *big.Int, *big.Int, tracing.BalanceChangeReason) { called["OnBalanceChange"] = true },
+			OnNonceChange:   func(common.Address, uint64, uint64) { called["OnNonceChange"] = true },
+			OnCodeChange:    func(common.Address, common.Hash, []byte, common.Hash, []byte) { called["OnCodeChange"] = true },
+			OnStorageChange: func(common.Address, common.Hash, common.Hash, common.Hash) { called["OnStorageChange"] = true },
+			OnLog:           func(*types.Log) { called["OnLog"] = true },
+		},
+		GetResult: func() (json.RawMessage, error) { return json.RawMessage("{}"), nil },
+		Stop:      func(error) {},
+	}
+
+	lt := &LiveTracer{cur: stub}
+	hooks := lt.Hooks()
+
+	hooks.OnEnter(0, 0, common.Address{}, common.Address{}, nil, 0, nil)
+	hooks.OnExit(0, nil, 0, nil, false)
+	hooks.OnOpcode(0, 0, 0, 0, nil, nil, 0, nil)
+	hooks.OnFault(0, 0, 0, 0, nil, 0, nil)
+	hooks.OnGasChange(0, 0, 0)
+	hooks.OnBalanceChange(common.Address{}, new(big.Int), new(big.Int), 0)
+	hooks.OnNonceChange(common.Address{}, 0, 0)
+	hooks.OnCodeChange(common.Address{}, common.Hash{}, nil, common.Hash{}, nil)
+	hooks.OnStorageChange(common.Address{}, common.Hash{}, common.Hash{}, common.Hash{})
+	hooks.OnLog(&types.Log{})
+
+	for _, name := range []string{
+		"OnEnter", "OnExit", "OnOpcode", "OnFault", "OnGasChange",
+		"OnBalanceChange", "OnNonceChange", "OnCodeChange", "OnStorageChange", "OnLog",
+	} {
+		if !called[name] {
+			t.Errorf("hook %s on the active per-tx tracer was never invoked", name)
+		}
+	}
+}
+
+// TestLiveTracerProxyNilCur verifies that the proxy hooks are no-ops
+// (rather than panicking) when no transaction is currently active.
+func TestLiveTracerProxyNilCur(t *testing.T) {
+	lt := &LiveTracer{}
+	hooks := lt.Hooks()
+	hooks.OnEnter(0, 0, common.Address{}, common.Address{}, nil, 0, nil)
+	hooks.OnLog(&types.Log{})
+}
+
+type stubSink struct {
+	written []FrameResult
+}
+
+func (s *stubSink) Write(fr FrameResult) error {
+	s.written = append(s.written, fr)
+	return nil
+}
+
+func (s *stubSink) Close() error { return nil }
+
+// TestLiveTracerOnTxEndNilReceipt verifies that onTxEnd does not dereference
+// a nil receipt when a transaction failed before producing one, and that it
+// still reports the tx hash captured at onTxStart.
+func TestLiveTracerOnTxEndNilReceipt(t *testing.T) {
+	sink := &stubSink{}
+	stub := &Tracer{
+		Hooks:     &tracing.Hooks{},
+		GetResult: func() (json.RawMessage, error) { return json.RawMessage("{}"), nil },
+		Stop:      func(error) {},
+	}
+	lt := &LiveTracer{cur: stub, sink: sink, txHash: common.Hash{0x42}}
+
+	lt.onTxEnd(nil, errFakeTxFailure)
+
+	if len(sink.written) != 1 {
+		t.Fatalf("expected 1 frame written, got %d", len(sink.written))
+	}
+	if sink.written[0].TxHash != (common.Hash{0x42}) {
+		t.Errorf("expected fallback tx hash %x, got %x", common.Hash{0x42}, sink.written[0].TxHash)
+	}
+}
+
+var errFakeTxFailure = errors.New("fake tx failure")
+
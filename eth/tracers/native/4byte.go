@@ -0,0 +1,142 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package native
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func init() {
+	tracers.DefaultDirectory.Register("4byteTracer", newFourByteTracer, false)
+}
+
+// fourByteTracer searches for 4byte-identifiers, and collects them for
+// post-processing. It collects the method identifiers along with the size
+// of the supplied data, so a reversed signature can be matched against the
+// size of the call data.
+//
+// Example:
+//
+//	> debug.traceTransaction("0x...", {tracer: "4byteTracer"})
+//	{
+//	  "0x27dc297e-128": 1,
+//	  "0x38cc4831-0": 2,
+//	  "0x524f3889-96": 1
+//	}
+type fourByteTracer struct {
+	noopTracer
+	ids         map[string]int // ids aggregates the 4byte ids found
+	precompiles map[common.Address]struct{}
+	reason      error // Textual reason for the interruption
+
+	blockNumber uint64
+	txHash      common.Hash
+}
+
+func newFourByteTracer(ctx *tracers.Context, _ json.RawMessage, chainConfig *params.ChainConfig) (*tracers.Tracer, error) {
+	t := &fourByteTracer{
+		ids: make(map[string]int),
+	}
+	if ctx != nil {
+		t.blockNumber = ctx.BlockNumber
+		t.txHash = ctx.TxHash
+	}
+	return &tracers.Tracer{
+		Hooks: &tracing.Hooks{
+			OnTxStart: t.OnTxStart,
+			OnEnter:   t.OnEnter,
+		},
+		GetResult: t.GetResult,
+		Stop:      t.Stop,
+	}, nil
+}
+
+// OnTxStart computes the set of precompile addresses active for the block
+// being traced, so that calls into them can be skipped: precompiles take raw
+// byte blobs rather than ABI-encoded (selector, args) calldata.
+func (t *fourByteTracer) OnTxStart(env *tracing.VMContext, tx *types.Transaction, from common.Address) {
+	rules := env.ChainConfig.Rules(env.BlockNumber, env.Random != nil, env.Time)
+	t.precompiles = make(map[common.Address]struct{})
+	for _, addr := range vm.ActivePrecompiles(rules) {
+		t.precompiles[addr] = struct{}{}
+	}
+}
+
+// OnEnter is invoked for every call frame entered while tracing, including
+// the root call. It records the selector of any non-precompile CALL-family
+// frame whose input carries at least a 4-byte selector.
+func (t *fourByteTracer) OnEnter(depth int, typ byte, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	op := vm.OpCode(typ)
+	switch op {
+	case vm.CALL, vm.STATICCALL, vm.DELEGATECALL, vm.CALLCODE:
+	default:
+		// Ignore CREATE/CREATE2 frames: their input is init code, not a
+		// selector-prefixed ABI call.
+		return
+	}
+	if _, ok := t.precompiles[to]; ok {
+		return
+	}
+	if len(input) < 4 {
+		return
+	}
+	t.store(input[:4], len(input)-4)
+}
+
+func (t *fourByteTracer) store(id []byte, size int) {
+	key := fmt.Sprintf("0x%x-%d", id, size)
+	t.ids[key]++
+}
+
+// GetResult returns the json-encoded selector histogram, and any error
+// arising from forceful termination (via `Stop`). When constructed with a
+// non-zero transaction hash (e.g. as part of a block/chain trace or a live
+// tracer), the result is stamped with the block number and tx hash so
+// callers can correlate it without a parallel index.
+func (t *fourByteTracer) GetResult() (json.RawMessage, error) {
+	var (
+		res []byte
+		err error
+	)
+	if t.txHash != (common.Hash{}) {
+		res, err = json.Marshal(struct {
+			BlockNumber uint64         `json:"blockNumber,omitempty"`
+			TxHash      common.Hash    `json:"txHash"`
+			Selectors   map[string]int `json:"selectors"`
+		}{t.blockNumber, t.txHash, t.ids})
+	} else {
+		res, err = json.Marshal(t.ids)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return json.RawMessage(res), t.reason
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (t *fourByteTracer) Stop(err error) {
+	t.reason = err
+}
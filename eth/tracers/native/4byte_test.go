@@ -0,0 +1,63 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package native
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+)
+
+// TestFourByteTracerStampsContext verifies that a tracer constructed with a
+// non-zero ctx.TxHash stamps the block number and tx hash into its result,
+// while a tracer constructed without one (e.g. the plain on-demand path)
+// keeps its original, un-nested selector histogram for compatibility.
+func TestFourByteTracerStampsContext(t *testing.T) {
+	tr, err := newFourByteTracer(&tracers.Context{BlockNumber: 42, TxHash: common.Hash{9}}, nil, nil)
+	if err != nil {
+		t.Fatalf("newFourByteTracer: %v", err)
+	}
+	res, resErr := tr.GetResult()
+	if resErr != nil {
+		t.Fatalf("GetResult: %v", resErr)
+	}
+	var out struct {
+		BlockNumber uint64      `json:"blockNumber"`
+		TxHash      common.Hash `json:"txHash"`
+	}
+	if err := json.Unmarshal(res, &out); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if out.BlockNumber != 42 || out.TxHash != (common.Hash{9}) {
+		t.Errorf("expected stamped blockNumber=42 txHash=%x, got blockNumber=%d txHash=%x", common.Hash{9}, out.BlockNumber, out.TxHash)
+	}
+
+	plain, err := newFourByteTracer(nil, nil, nil)
+	if err != nil {
+		t.Fatalf("newFourByteTracer: %v", err)
+	}
+	res, resErr = plain.GetResult()
+	if resErr != nil {
+		t.Fatalf("GetResult: %v", resErr)
+	}
+	var ids map[string]int
+	if err := json.Unmarshal(res, &ids); err != nil {
+		t.Errorf("expected plain selector histogram without ctx, got %s: %v", res, err)
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package native
+
+import (
+	"encoding/json"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func init() {
+	tracers.DefaultDirectory.Register("logsTracer", newLogsTracer, false)
+}
+
+// logsTracerConfig mirrors the filter semantics of eth_getLogs: Addresses is
+// an OR-list of emitter addresses (empty matches any), and each entry of
+// Topics is itself an OR-list matched positionally against the log's
+// topics (an empty entry matches any topic at that position).
+type logsTracerConfig struct {
+	Addresses []common.Address `json:"addresses"`
+	Topics    [][]common.Hash  `json:"topics"`
+}
+
+type logsTracer struct {
+	noopTracer
+	config logsTracerConfig
+	logs   map[common.Hash][]*types.Log // keyed by tx hash
+	order  []common.Hash                // tx hashes in the order first seen
+	reason error
+
+	blockNumber uint64
+	txHash      common.Hash
+}
+
+func newLogsTracer(ctx *tracers.Context, cfg json.RawMessage, _ *params.ChainConfig) (*tracers.Tracer, error) {
+	var config logsTracerConfig
+	if cfg != nil {
+		if err := json.Unmarshal(cfg, &config); err != nil {
+			return nil, err
+		}
+	}
+	t := &logsTracer{
+		config: config,
+		logs:   make(map[common.Hash][]*types.Log),
+	}
+	if ctx != nil {
+		t.blockNumber = ctx.BlockNumber
+		t.txHash = ctx.TxHash
+	}
+	return &tracers.Tracer{
+		Hooks: &tracing.Hooks{
+			OnLog: t.OnLog,
+		},
+		GetResult: t.GetResult,
+		Stop:      t.Stop,
+	}, nil
+}
+
+// OnLog implements tracing.Hooks. The EVM hands us a fully populated
+// *types.Log (address, all topics, data, and block/tx/log indices), so no
+// manual memory copying or topic-count truncation is needed here.
+func (t *logsTracer) OnLog(log *types.Log) {
+	if !t.matches(log) {
+		return
+	}
+	if _, ok := t.logs[log.TxHash]; !ok {
+		t.order = append(t.order, log.TxHash)
+	}
+	t.logs[log.TxHash] = append(t.logs[log.TxHash], log)
+}
+
+// matches reports whether log passes the configured address/topics filter,
+// using the same semantics as eth_getLogs.
+func (t *logsTracer) matches(log *types.Log) bool {
+	if len(t.config.Addresses) > 0 {
+		found := false
+		for _, addr := range t.config.Addresses {
+			if addr == log.Address {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(t.config.Topics) > len(log.Topics) {
+		return false
+	}
+	for i, want := range t.config.Topics {
+		if len(want) == 0 {
+			continue // wildcard at this position
+		}
+		found := false
+		for _, topic := range want {
+			if topic == log.Topics[i] {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// GetResult returns the json-encoded logs, grouped per transaction hash in
+// the order transactions were first seen, and any error arising from the
+// encoding or forceful termination (via `Stop`). When constructed with a
+// non-zero transaction hash (e.g. as part of a block/chain trace or a live
+// tracer), the result is stamped with the block number and tx hash so
+// callers can correlate it without a parallel index.
+func (t *logsTracer) GetResult() (json.RawMessage, error) {
+	type txLogs struct {
+		TxHash common.Hash  `json:"txHash"`
+		Logs   []*types.Log `json:"logs"`
+	}
+	result := make([]txLogs, 0, len(t.order))
+	for _, txHash := range t.order {
+		result = append(result, txLogs{TxHash: txHash, Logs: t.logs[txHash]})
+	}
+
+	var (
+		res []byte
+		err error
+	)
+	if t.txHash != (common.Hash{}) {
+		res, err = json.Marshal(struct {
+			BlockNumber uint64      `json:"blockNumber,omitempty"`
+			TxHash      common.Hash `json:"txHash"`
+			Logs        []txLogs    `json:"logs"`
+		}{t.blockNumber, t.txHash, result})
+	} else {
+		res, err = json.Marshal(result)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return json.RawMessage(res), t.reason
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (t *logsTracer) Stop(err error) {
+	t.reason = err
+}
@@ -0,0 +1,123 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package native
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+)
+
+func TestLogsTracerFiltersByAddressAndTopic(t *testing.T) {
+	addrA := common.Address{1}
+	addrB := common.Address{2}
+	topicWanted := common.Hash{0xA}
+	topicOther := common.Hash{0xB}
+
+	tr := &logsTracer{
+		config: logsTracerConfig{
+			Addresses: []common.Address{addrA},
+			Topics:    [][]common.Hash{{topicWanted}},
+		},
+		logs: make(map[common.Hash][]*types.Log),
+	}
+
+	tr.OnLog(&types.Log{Address: addrA, Topics: []common.Hash{topicWanted}, TxHash: common.Hash{0x1}})
+	tr.OnLog(&types.Log{Address: addrB, Topics: []common.Hash{topicWanted}, TxHash: common.Hash{0x2}}) // wrong address
+	tr.OnLog(&types.Log{Address: addrA, Topics: []common.Hash{topicOther}, TxHash: common.Hash{0x3}})  // wrong topic
+
+	if len(tr.order) != 1 || tr.order[0] != (common.Hash{0x1}) {
+		t.Fatalf("expected only the matching tx hash to be recorded, got %v", tr.order)
+	}
+	if got := len(tr.logs[common.Hash{0x1}]); got != 1 {
+		t.Fatalf("expected 1 matching log, got %d", got)
+	}
+}
+
+func TestLogsTracerWildcardTopicMatchesAny(t *testing.T) {
+	addr := common.Address{1}
+	tr := &logsTracer{
+		config: logsTracerConfig{
+			Topics: [][]common.Hash{{}}, // wildcard at position 0
+		},
+		logs: make(map[common.Hash][]*types.Log),
+	}
+
+	tr.OnLog(&types.Log{Address: addr, Topics: []common.Hash{{0xAB}}, TxHash: common.Hash{0x1}})
+
+	if len(tr.logs[common.Hash{0x1}]) != 1 {
+		t.Fatalf("expected wildcard topic entry to match any topic value")
+	}
+}
+
+func TestLogsTracerGroupsByTxInFirstSeenOrder(t *testing.T) {
+	tr := &logsTracer{logs: make(map[common.Hash][]*types.Log)}
+
+	// Topics is non-nil, as for a real LOG0: types.Log's JSON decoding
+	// rejects a null "topics" field.
+	tr.OnLog(&types.Log{Topics: []common.Hash{}, TxHash: common.Hash{0x2}})
+	tr.OnLog(&types.Log{Topics: []common.Hash{}, TxHash: common.Hash{0x1}})
+	tr.OnLog(&types.Log{Topics: []common.Hash{}, TxHash: common.Hash{0x2}})
+
+	res, err := tr.GetResult()
+	if err != nil {
+		t.Fatalf("GetResult: %v", err)
+	}
+
+	var out []struct {
+		TxHash common.Hash  `json:"txHash"`
+		Logs   []*types.Log `json:"logs"`
+	}
+	if err := json.Unmarshal(res, &out); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if len(out) != 2 {
+		t.Fatalf("expected 2 tx groups, got %d", len(out))
+	}
+	if out[0].TxHash != (common.Hash{0x2}) || len(out[0].Logs) != 2 {
+		t.Errorf("expected tx 0x2 first (first seen) with 2 logs, got %+v", out[0])
+	}
+	if out[1].TxHash != (common.Hash{0x1}) || len(out[1].Logs) != 1 {
+		t.Errorf("expected tx 0x1 second with 1 log, got %+v", out[1])
+	}
+}
+
+// TestLogsTracerStampsContext verifies that a tracer constructed with a
+// non-zero ctx.TxHash stamps the block number and tx hash into its result.
+func TestLogsTracerStampsContext(t *testing.T) {
+	tr, err := newLogsTracer(&tracers.Context{BlockNumber: 3, TxHash: common.Hash{7}}, nil, nil)
+	if err != nil {
+		t.Fatalf("newLogsTracer: %v", err)
+	}
+	res, resErr := tr.GetResult()
+	if resErr != nil {
+		t.Fatalf("GetResult: %v", resErr)
+	}
+	var out struct {
+		BlockNumber uint64      `json:"blockNumber"`
+		TxHash      common.Hash `json:"txHash"`
+	}
+	if err := json.Unmarshal(res, &out); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if out.BlockNumber != 3 || out.TxHash != (common.Hash{7}) {
+		t.Errorf("expected stamped blockNumber=3 txHash=%x, got blockNumber=%d txHash=%x", common.Hash{7}, out.BlockNumber, out.TxHash)
+	}
+}
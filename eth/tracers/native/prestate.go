@@ -17,35 +17,48 @@
 package native
 
 import (
-	"encoding/hex"
+	"bytes"
 	"encoding/json"
+	"math/big"
+
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/tracing"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/eth/tracers"
 	"github.com/ethereum/go-ethereum/eth/tracers/internal"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/params"
-	"github.com/holiman/uint256"
 )
 
-//go:generate go run github.com/fjl/gencodec -type account -field-override accountMarshaling -out gen_account_json.go
-
 func init() {
 	tracers.DefaultDirectory.Register("prestateTracer", newPrestateTracer, false)
+	tracers.LiveDirectory.Register("prestateTracer", newPrestateTracer, false)
 }
 
-type event struct {
-	Caller  common.Address `json:"caller,omitempty"`
-	Topics0 uint256.Int    `json:"topics0,omitempty"`
-	Data    []string       `json:"data,omitempty"`
+type account struct {
+	Balance *hexutil.Big                `json:"balance,omitempty"`
+	Code    hexutil.Bytes               `json:"code,omitempty"`
+	Nonce   uint64                      `json:"nonce,omitempty"`
+	Storage map[common.Hash]common.Hash `json:"storage,omitempty"`
 }
 
+type stateMap = map[common.Address]*account
+
 type prestateTracer struct {
 	noopTracer
-	pre    []event
-	isFail bool
-	reason error // Textual reason for the interruption
+	env         *tracing.VMContext
+	pre         stateMap
+	post        stateMap
+	config      prestateTracerConfig
+	isFail      bool
+	reason      error // Textual reason for the interruption
+	created     map[common.Address]bool
+	deleted     map[common.Address]bool
+	blockNumber uint64
+	txHash      common.Hash
 }
 
 type prestateTracerConfig struct {
@@ -54,73 +67,222 @@ type prestateTracerConfig struct {
 
 func newPrestateTracer(ctx *tracers.Context, cfg json.RawMessage, chainConfig *params.ChainConfig) (*tracers.Tracer, error) {
 	var config prestateTracerConfig
-	if err := json.Unmarshal(cfg, &config); err != nil {
-		return nil, err
+	if cfg != nil {
+		if err := json.Unmarshal(cfg, &config); err != nil {
+			return nil, err
+		}
 	}
 	t := &prestateTracer{
-		pre: []event{},
+		pre:     stateMap{},
+		post:    stateMap{},
+		config:  config,
+		created: make(map[common.Address]bool),
+		deleted: make(map[common.Address]bool),
+	}
+	if ctx != nil {
+		t.blockNumber = ctx.BlockNumber
+		t.txHash = ctx.TxHash
 	}
 	return &tracers.Tracer{
 		Hooks: &tracing.Hooks{
-			OnTxStart: t.OnTxStart,
-			OnTxEnd:   t.OnTxEnd,
-			OnOpcode:  t.OnOpcode,
+			OnTxStart:       t.OnTxStart,
+			OnTxEnd:         t.OnTxEnd,
+			OnOpcode:        t.OnOpcode,
+			OnBalanceChange: t.OnBalanceChange,
+			OnNonceChange:   t.OnNonceChange,
+			OnCodeChange:    t.OnCodeChange,
+			OnStorageChange: t.OnStorageChange,
 		},
 		GetResult: t.GetResult,
 		Stop:      t.Stop,
 	}, nil
 }
 
-// OnOpcode implements the EVMLogger interface to trace a single step of VM execution.
+// OnTxStart seeds the prestate with the accounts touched unconditionally by
+// every transaction: the sender, the recipient (if any) and the coinbase.
+// For a contract-creation transaction (tx.To() == nil) there is no
+// recipient to seed; instead the about-to-be-created address is recorded in
+// t.created so OnTxEnd can keep it out of the prestate entirely.
+func (t *prestateTracer) OnTxStart(env *tracing.VMContext, tx *types.Transaction, from common.Address) {
+	t.env = env
+	t.lookupAccount(from)
+	t.lookupAccount(env.Coinbase)
+	if to := tx.To(); to != nil {
+		t.lookupAccount(*to)
+	} else {
+		t.created[crypto.CreateAddress(from, tx.Nonce())] = true
+	}
+}
+
+// OnTxEnd drops accounts created during the transaction from the prestate
+// (they did not exist beforehand, in either diff or non-diff mode), then,
+// in diff mode, prunes post fields (and whole accounts) whose
+// post-transaction value matches the prestate.
+func (t *prestateTracer) OnTxEnd(receipt *types.Receipt, err error) {
+	if err != nil {
+		return
+	}
+	for addr := range t.created {
+		delete(t.pre, addr)
+	}
+	if !t.config.DiffMode {
+		return
+	}
+	for addr, postAcc := range t.post {
+		preAcc, ok := t.pre[addr]
+		if !ok {
+			continue
+		}
+		if postAcc.Balance != nil && preAcc.Balance != nil && postAcc.Balance.ToInt().Cmp(preAcc.Balance.ToInt()) == 0 {
+			postAcc.Balance = nil
+		}
+		if postAcc.Nonce == preAcc.Nonce {
+			postAcc.Nonce = 0
+		}
+		if bytes.Equal(postAcc.Code, preAcc.Code) {
+			postAcc.Code = nil
+		}
+		for slot, val := range postAcc.Storage {
+			if preAcc.Storage[slot] == val {
+				delete(postAcc.Storage, slot)
+			}
+		}
+		if len(postAcc.Storage) == 0 {
+			postAcc.Storage = nil
+		}
+		if postAcc.Balance == nil && postAcc.Nonce == 0 && postAcc.Code == nil && postAcc.Storage == nil && !t.deleted[addr] {
+			delete(t.post, addr)
+		}
+	}
+}
+
+// OnOpcode captures accounts and storage slots that are only read during
+// execution and therefore never trigger one of the OnXxxChange hooks below.
 func (t *prestateTracer) OnOpcode(pc uint64, opcode byte, gas, cost uint64, scope tracing.OpContext, rData []byte, depth int, err error) {
-	if opcode == 0xfd {
-		t.isFail = true
+	if err != nil {
 		return
 	}
-	if opcode > 0xa0 && opcode <= 0xa4 {
-		stackData := scope.StackData()
-		stackLen := len(stackData)
+	op := vm.OpCode(opcode)
+	stackData := scope.StackData()
+	stackLen := len(stackData)
 
-		caller := scope.Address()
-		offset := stackData[stackLen-1]
-		size := stackData[stackLen-2]
-		topics0 := stackData[stackLen-3]
-
-		data, err := internal.GetMemoryCopyPadded(scope.MemoryData(), int64(offset.Uint64()), int64(size.Uint64()))
-		if err != nil {
-			log.Warn("failed to copy CREATE2 input", "err", err, "tracer", "prestateTracer", "offset", offset, "size", size)
-			return
+	switch op {
+	case vm.SLOAD, vm.SSTORE:
+		if stackLen >= 1 {
+			slot := common.Hash(stackData[stackLen-1].Bytes32())
+			t.lookupStorage(scope.Address(), slot)
+		}
+	case vm.EXTCODECOPY, vm.EXTCODEHASH, vm.EXTCODESIZE, vm.BALANCE:
+		if stackLen >= 1 {
+			t.lookupAccount(common.Address(stackData[stackLen-1].Bytes20()))
 		}
-		var dataRes []string
-		for i := 0; i < len(data); i += 32 {
-			end := i + 32
-			if end > len(data) {
-				end = len(data)
+	case vm.SELFDESTRUCT:
+		if stackLen >= 1 {
+			addr := scope.Address()
+			t.lookupAccount(addr)
+			t.lookupAccount(common.Address(stackData[stackLen-1].Bytes20()))
+			t.deleted[addr] = true
+		}
+	case vm.CALL, vm.CALLCODE, vm.DELEGATECALL, vm.STATICCALL:
+		if stackLen >= 2 {
+			t.lookupAccount(common.Address(stackData[stackLen-2].Bytes20()))
+		}
+	case vm.CREATE:
+		caller := scope.Address()
+		addr := crypto.CreateAddress(caller, t.env.StateDB.GetNonce(caller))
+		t.created[addr] = true
+		t.lookupAccount(addr)
+	case vm.CREATE2:
+		if stackLen >= 4 {
+			offset, size, salt := stackData[stackLen-2], stackData[stackLen-3], stackData[stackLen-4]
+			init, err := internal.GetMemoryCopyPadded(scope.MemoryData(), int64(offset.Uint64()), int64(size.Uint64()))
+			if err != nil {
+				log.Warn("failed to copy CREATE2 input", "err", err, "tracer", "prestateTracer", "offset", offset, "size", size)
+				return
 			}
-			slice := data[i:end]
-			hexString := "0x" + hex.EncodeToString(slice)
-			dataRes = append(dataRes, hexString)
+			caller := scope.Address()
+			addr := crypto.CreateAddress2(caller, salt.Bytes32(), crypto.Keccak256(init))
+			t.created[addr] = true
+			t.lookupAccount(addr)
 		}
-		t.lookupLog(caller, dataRes, topics0)
 	}
 }
 
-func (t *prestateTracer) OnTxStart(env *tracing.VMContext, tx *types.Transaction, from common.Address) {
+// OnBalanceChange implements tracing.Hooks.
+func (t *prestateTracer) OnBalanceChange(addr common.Address, prev, new *big.Int, reason tracing.BalanceChangeReason) {
+	if _, ok := t.pre[addr]; !ok {
+		t.pre[addr] = t.newAccount(addr)
+		t.pre[addr].Balance = (*hexutil.Big)(prev)
+	}
+	if t.config.DiffMode {
+		t.ensurePost(addr).Balance = (*hexutil.Big)(new)
+	}
 }
 
-func (t *prestateTracer) OnTxEnd(receipt *types.Receipt, err error) {
+// OnNonceChange implements tracing.Hooks.
+func (t *prestateTracer) OnNonceChange(addr common.Address, prev, new uint64) {
+	if _, ok := t.pre[addr]; !ok {
+		t.pre[addr] = t.newAccount(addr)
+		t.pre[addr].Nonce = prev
+	}
+	if t.config.DiffMode {
+		t.ensurePost(addr).Nonce = new
+	}
+}
+
+// OnCodeChange implements tracing.Hooks.
+func (t *prestateTracer) OnCodeChange(addr common.Address, prevCodeHash common.Hash, prevCode []byte, codeHash common.Hash, code []byte) {
+	if _, ok := t.pre[addr]; !ok {
+		t.pre[addr] = t.newAccount(addr)
+		t.pre[addr].Code = prevCode
+	}
+	if t.config.DiffMode {
+		t.ensurePost(addr).Code = code
+	}
 }
 
-// GetResult returns the json-encoded nested list of call traces, and any
+// OnStorageChange implements tracing.Hooks.
+func (t *prestateTracer) OnStorageChange(addr common.Address, slot common.Hash, prev, new common.Hash) {
+	t.lookupAccount(addr)
+	if _, ok := t.pre[addr].Storage[slot]; !ok {
+		t.pre[addr].Storage[slot] = prev
+	}
+	if t.config.DiffMode {
+		post := t.ensurePost(addr)
+		if post.Storage == nil {
+			post.Storage = make(map[common.Hash]common.Hash)
+		}
+		post.Storage[slot] = new
+	}
+}
+
+// GetResult returns the json-encoded prestate (or pre/post diff), and any
 // error arising from the encoding or forceful termination (via `Stop`).
 func (t *prestateTracer) GetResult() (json.RawMessage, error) {
 	var res []byte
 	var err error
-	res, err = json.Marshal(struct {
-		Event  []event `json:"event"`
-		IsFail bool    `json:"isFail"`
-		Reason error   `json:"reason"`
-	}{t.pre, t.isFail, t.reason})
+	switch {
+	case t.txHash != (common.Hash{}) && t.config.DiffMode:
+		res, err = json.Marshal(struct {
+			BlockNumber uint64      `json:"blockNumber,omitempty"`
+			TxHash      common.Hash `json:"txHash"`
+			Pre         stateMap    `json:"pre"`
+			Post        stateMap    `json:"post"`
+		}{t.blockNumber, t.txHash, t.pre, t.post})
+	case t.txHash != (common.Hash{}):
+		res, err = json.Marshal(struct {
+			BlockNumber uint64      `json:"blockNumber,omitempty"`
+			TxHash      common.Hash `json:"txHash"`
+			Pre         stateMap    `json:"pre"`
+		}{t.blockNumber, t.txHash, t.pre})
+	case t.config.DiffMode:
+		res, err = json.Marshal(struct {
+			Pre  stateMap `json:"pre"`
+			Post stateMap `json:"post"`
+		}{t.pre, t.post})
+	default:
+		res, err = json.Marshal(t.pre)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -133,6 +295,41 @@ func (t *prestateTracer) Stop(err error) {
 	t.isFail = true
 }
 
-func (t *prestateTracer) lookupLog(addr common.Address, data []string, topics0 uint256.Int) {
-	t.pre = append(t.pre, event{addr, topics0, data})
+// newAccount snapshots the current on-chain state of addr, without
+// registering it in t.pre/t.post.
+func (t *prestateTracer) newAccount(addr common.Address) *account {
+	return &account{
+		Balance: (*hexutil.Big)(t.env.StateDB.GetBalance(addr).ToBig()),
+		Nonce:   t.env.StateDB.GetNonce(addr),
+		Code:    t.env.StateDB.GetCode(addr),
+		Storage: make(map[common.Hash]common.Hash),
+	}
+}
+
+// lookupAccount ensures addr is present in the prestate map, populated with
+// its balance/nonce/code as they were before the transaction executed.
+func (t *prestateTracer) lookupAccount(addr common.Address) {
+	if _, ok := t.pre[addr]; ok {
+		return
+	}
+	t.pre[addr] = t.newAccount(addr)
+}
+
+// lookupStorage ensures addr and slot are present in the prestate map.
+func (t *prestateTracer) lookupStorage(addr common.Address, key common.Hash) {
+	t.lookupAccount(addr)
+	if _, ok := t.pre[addr].Storage[key]; ok {
+		return
+	}
+	t.pre[addr].Storage[key] = t.env.StateDB.GetState(addr, key)
+}
+
+// ensurePost returns the post-state account for addr, creating it on first use.
+func (t *prestateTracer) ensurePost(addr common.Address) *account {
+	if acc, ok := t.post[addr]; ok {
+		return acc
+	}
+	acc := &account{}
+	t.post[addr] = acc
+	return acc
 }
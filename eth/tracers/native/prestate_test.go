@@ -0,0 +1,117 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package native
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// TestPrestateTracerCreatedAddressOmitted verifies that an address created
+// during the traced transaction never lingers in the prestate, whether it
+// was created by a top-level contract-creation tx (tracked directly by
+// OnTxStart) or by a CREATE/CREATE2 opcode nested in execution (tracked by
+// OnOpcode).
+func TestPrestateTracerCreatedAddressOmitted(t *testing.T) {
+	from := common.Address{1}
+
+	rootCreated := crypto.CreateAddress(from, 7)
+	nestedCreated := crypto.CreateAddress(common.Address{2}, 0)
+
+	for _, diffMode := range []bool{false, true} {
+		tr := &prestateTracer{
+			pre:     stateMap{},
+			post:    stateMap{},
+			config:  prestateTracerConfig{DiffMode: diffMode},
+			created: map[common.Address]bool{rootCreated: true, nestedCreated: true},
+			deleted: map[common.Address]bool{},
+		}
+		// Simulate some hook having touched the created addresses, the way
+		// OnCodeChange would when the new contract's code is installed.
+		tr.pre[rootCreated] = &account{}
+		tr.pre[nestedCreated] = &account{}
+
+		tr.OnTxEnd(nil, nil)
+
+		if _, ok := tr.pre[rootCreated]; ok {
+			t.Errorf("diffMode=%v: root-created address leaked into prestate", diffMode)
+		}
+		if _, ok := tr.pre[nestedCreated]; ok {
+			t.Errorf("diffMode=%v: nested-created address leaked into prestate", diffMode)
+		}
+	}
+}
+
+// TestPrestateTracerDiffModePrunesUnchanged verifies that in diff mode,
+// fields (and whole accounts) whose post value matches the prestate are
+// dropped, matching the well-known geth prestate JSON.
+func TestPrestateTracerDiffModePrunesUnchanged(t *testing.T) {
+	addr := common.Address{3}
+	tr := &prestateTracer{
+		pre:     stateMap{addr: {Nonce: 1}},
+		post:    stateMap{addr: {Nonce: 1}},
+		config:  prestateTracerConfig{DiffMode: true},
+		created: map[common.Address]bool{},
+		deleted: map[common.Address]bool{},
+	}
+
+	tr.OnTxEnd(nil, nil)
+
+	if _, ok := tr.post[addr]; ok {
+		t.Errorf("expected unchanged account to be pruned from post, still present: %+v", tr.post[addr])
+	}
+}
+
+// TestPrestateTracerStampsContextWithoutDiffMode verifies that blockNumber
+// and txHash are stamped into the result whenever ctx.TxHash is set, even
+// when DiffMode is false — e.g. a plain (non-diff) prestateTracer run as
+// part of debug_traceBlockByNumber/traceChain or a live tracer.
+func TestPrestateTracerStampsContextWithoutDiffMode(t *testing.T) {
+	addr := common.Address{4}
+	tr := &prestateTracer{
+		pre:         stateMap{addr: {Nonce: 1}},
+		post:        stateMap{},
+		config:      prestateTracerConfig{DiffMode: false},
+		created:     map[common.Address]bool{},
+		deleted:     map[common.Address]bool{},
+		blockNumber: 11,
+		txHash:      common.Hash{6},
+	}
+
+	res, err := tr.GetResult()
+	if err != nil {
+		t.Fatalf("GetResult: %v", err)
+	}
+
+	var out struct {
+		BlockNumber uint64      `json:"blockNumber"`
+		TxHash      common.Hash `json:"txHash"`
+		Pre         stateMap    `json:"pre"`
+	}
+	if err := json.Unmarshal(res, &out); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if out.BlockNumber != 11 || out.TxHash != (common.Hash{6}) {
+		t.Errorf("expected stamped blockNumber=11 txHash=%x, got blockNumber=%d txHash=%x", common.Hash{6}, out.BlockNumber, out.TxHash)
+	}
+	if len(out.Pre) != 1 {
+		t.Errorf("expected pre state to still be present, got %+v", out.Pre)
+	}
+}
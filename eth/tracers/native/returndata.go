@@ -0,0 +1,188 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package native
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func init() {
+	tracers.DefaultDirectory.Register("returnDataTracer", newReturnDataTracer, false)
+}
+
+var (
+	errorSelector = crypto4Byte("Error(string)")
+	panicSelector = crypto4Byte("Panic(uint256)")
+)
+
+// revertFrame is a single call frame that reverted, together with its
+// decoded reason when one could be extracted.
+type revertFrame struct {
+	From   common.Address `json:"from"`
+	To     common.Address `json:"to"`
+	Input  hexutil.Bytes  `json:"input,omitempty"`
+	Output hexutil.Bytes  `json:"output"`
+	Reason string         `json:"reason,omitempty"`
+	Depth  int            `json:"depth"`
+	Calls  []*revertFrame `json:"calls,omitempty"`
+}
+
+type returnDataTracer struct {
+	noopTracer
+	stack  []*revertFrame
+	roots  []*revertFrame
+	reason error
+
+	blockNumber uint64
+	txHash      common.Hash
+}
+
+func newReturnDataTracer(ctx *tracers.Context, _ json.RawMessage, _ *params.ChainConfig) (*tracers.Tracer, error) {
+	t := &returnDataTracer{}
+	if ctx != nil {
+		t.blockNumber = ctx.BlockNumber
+		t.txHash = ctx.TxHash
+	}
+	return &tracers.Tracer{
+		Hooks: &tracing.Hooks{
+			OnEnter: t.OnEnter,
+			OnExit:  t.OnExit,
+		},
+		GetResult: t.GetResult,
+		Stop:      t.Stop,
+	}, nil
+}
+
+// OnEnter pushes a new, provisional frame onto the call stack. It is only
+// retained in the result if OnExit later finds that the frame reverted with
+// non-empty output.
+func (t *returnDataTracer) OnEnter(depth int, typ byte, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	t.stack = append(t.stack, &revertFrame{
+		From:  from,
+		To:    to,
+		Input: input,
+		Depth: depth,
+	})
+}
+
+// OnExit pops the current frame. A frame that itself reverted with output is
+// kept and attached to its parent. A frame that didn't revert is dropped,
+// but its own reverted descendants are promoted to its parent instead of
+// being discarded with it — this is the common "checked low-level call"
+// pattern, where an inner call reverts but the caller catches the failure
+// and returns normally, and the nested revert reason should still surface.
+func (t *returnDataTracer) OnExit(depth int, output []byte, gasUsed uint64, err error, reverted bool) {
+	n := len(t.stack)
+	if n == 0 {
+		return
+	}
+	frame := t.stack[n-1]
+	t.stack = t.stack[:n-1]
+
+	selfReverted := errors.Is(err, vm.ErrExecutionReverted) && len(output) > 0
+	if selfReverted {
+		frame.Output = output
+		frame.Reason = decodeRevertReason(output)
+	} else if len(frame.Calls) == 0 {
+		return
+	}
+
+	if len(t.stack) == 0 {
+		if selfReverted {
+			t.roots = append(t.roots, frame)
+		} else {
+			t.roots = append(t.roots, frame.Calls...)
+		}
+		return
+	}
+	parent := t.stack[len(t.stack)-1]
+	if selfReverted {
+		parent.Calls = append(parent.Calls, frame)
+	} else {
+		parent.Calls = append(parent.Calls, frame.Calls...)
+	}
+}
+
+// decodeRevertReason best-effort decodes the well-known Error(string) and
+// Panic(uint256) ABI-encoded revert payloads. It returns "" for any other
+// payload; the raw bytes are kept in the frame's Output either way.
+func decodeRevertReason(output []byte) string {
+	if len(output) < 4 {
+		return ""
+	}
+	switch {
+	case hasSelector(output, errorSelector):
+		reason, err := abi.UnpackRevert(output)
+		if err == nil {
+			return reason
+		}
+	case hasSelector(output, panicSelector):
+		return "panic: code " + new(big.Int).SetBytes(output[4:]).String()
+	}
+	return ""
+}
+
+func hasSelector(output []byte, selector [4]byte) bool {
+	return len(output) >= 4 && [4]byte(output[:4]) == selector
+}
+
+func crypto4Byte(sig string) [4]byte {
+	var sel [4]byte
+	copy(sel[:], crypto.Keccak256([]byte(sig))[:4])
+	return sel
+}
+
+// GetResult returns the json-encoded tree of reverted call frames, and any
+// error arising from the encoding or forceful termination (via `Stop`). When
+// constructed with a non-zero transaction hash (e.g. as part of a
+// block/chain trace or a live tracer), the result is stamped with the block
+// number and tx hash so callers can correlate it without a parallel index.
+func (t *returnDataTracer) GetResult() (json.RawMessage, error) {
+	var (
+		res []byte
+		err error
+	)
+	if t.txHash != (common.Hash{}) {
+		res, err = json.Marshal(struct {
+			BlockNumber uint64         `json:"blockNumber,omitempty"`
+			TxHash      common.Hash    `json:"txHash"`
+			Reverts     []*revertFrame `json:"reverts"`
+		}{t.blockNumber, t.txHash, t.roots})
+	} else {
+		res, err = json.Marshal(t.roots)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return json.RawMessage(res), t.reason
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (t *returnDataTracer) Stop(err error) {
+	t.reason = err
+}
@@ -0,0 +1,116 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package native
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+)
+
+func errorRevertData(msg string) []byte {
+	out := make([]byte, 4)
+	copy(out, errorSelector[:])
+	// Minimal ABI encoding of Error(string): offset(32) + length(32) + data.
+	out = append(out, make([]byte, 31)...)
+	out = append(out, 0x20)
+	lenWord := make([]byte, 32)
+	lenWord[31] = byte(len(msg))
+	out = append(out, lenWord...)
+	out = append(out, []byte(msg)...)
+	return out
+}
+
+// TestReturnDataTracerDirectRevert checks the simple case: a single call
+// frame that reverts with an Error(string) reason.
+func TestReturnDataTracerDirectRevert(t *testing.T) {
+	tr := &returnDataTracer{}
+	tr.OnEnter(0, 0, common.Address{1}, common.Address{2}, nil, 0, nil)
+	tr.OnExit(0, errorRevertData("boom"), 0, vm.ErrExecutionReverted, true)
+
+	if len(tr.roots) != 1 {
+		t.Fatalf("expected 1 root frame, got %d", len(tr.roots))
+	}
+	if tr.roots[0].Reason != "boom" {
+		t.Fatalf("expected reason %q, got %q", "boom", tr.roots[0].Reason)
+	}
+}
+
+// TestReturnDataTracerCheckedCallSwallowsRevert models the common
+// "checked low-level call" / try-catch pattern: A calls B, B reverts, but A
+// catches the failure and itself returns normally. The nested revert
+// reason from B must still surface under the root.
+func TestReturnDataTracerCheckedCallSwallowsRevert(t *testing.T) {
+	tr := &returnDataTracer{}
+
+	// Enter A.
+	tr.OnEnter(0, 0, common.Address{1}, common.Address{0xA}, nil, 0, nil)
+	// A calls B.
+	tr.OnEnter(1, 0, common.Address{0xA}, common.Address{0xB}, nil, 0, nil)
+	// B reverts.
+	tr.OnExit(1, errorRevertData("inner failure"), 0, vm.ErrExecutionReverted, true)
+	// A catches the failure and returns normally (no revert).
+	tr.OnExit(0, nil, 0, nil, false)
+
+	if len(tr.roots) != 1 {
+		t.Fatalf("expected B's revert to be promoted to root, got %d roots", len(tr.roots))
+	}
+	if got := tr.roots[0].Reason; got != "inner failure" {
+		t.Fatalf("expected promoted reason %q, got %q", "inner failure", got)
+	}
+	if tr.roots[0].To != (common.Address{0xB}) {
+		t.Fatalf("expected promoted frame to still be B's frame, got %x", tr.roots[0].To)
+	}
+}
+
+// TestReturnDataTracerStampsContext verifies that a tracer constructed with
+// a non-zero ctx.TxHash stamps the block number and tx hash into its result.
+func TestReturnDataTracerStampsContext(t *testing.T) {
+	tr, err := newReturnDataTracer(&tracers.Context{BlockNumber: 7, TxHash: common.Hash{5}}, nil, nil)
+	if err != nil {
+		t.Fatalf("newReturnDataTracer: %v", err)
+	}
+	res, resErr := tr.GetResult()
+	if resErr != nil {
+		t.Fatalf("GetResult: %v", resErr)
+	}
+	var out struct {
+		BlockNumber uint64      `json:"blockNumber"`
+		TxHash      common.Hash `json:"txHash"`
+	}
+	if err := json.Unmarshal(res, &out); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if out.BlockNumber != 7 || out.TxHash != (common.Hash{5}) {
+		t.Errorf("expected stamped blockNumber=7 txHash=%x, got blockNumber=%d txHash=%x", common.Hash{5}, out.BlockNumber, out.TxHash)
+	}
+}
+
+// TestReturnDataTracerSuccessDropped checks that a frame which neither
+// reverted nor has any reverted descendants is dropped entirely.
+func TestReturnDataTracerSuccessDropped(t *testing.T) {
+	tr := &returnDataTracer{}
+	tr.OnEnter(0, 0, common.Address{1}, common.Address{2}, nil, 0, nil)
+	tr.OnExit(0, nil, 0, nil, false)
+
+	if len(tr.roots) != 0 {
+		t.Fatalf("expected no roots for a clean call, got %d", len(tr.roots))
+	}
+}
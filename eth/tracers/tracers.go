@@ -0,0 +1,91 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package tracers is a collection of JavaScript and native Go transaction
+// tracers.
+package tracers
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Context contains some contextual infos for a transaction execution that is
+// not available from within the EVM object.
+type Context struct {
+	BlockHash   common.Hash // Hash of the block the tx is contained within (zero if dangling tx or call)
+	BlockNumber uint64      // Number of the block the tx is contained within (zero if dangling tx or call)
+	TxIndex     int         // Index of the transaction within a block (zero if dangling tx or call)
+	TxHash      common.Hash // Hash of the transaction being traced (zero if dangling call)
+}
+
+// Tracer bundles a tracer's tracing.Hooks with the functions for collecting
+// its result and stopping it.
+type Tracer struct {
+	*tracing.Hooks
+	GetResult func() (json.RawMessage, error)
+
+	// Stop terminates execution of the tracer at the first opportune moment.
+	Stop func(err error)
+}
+
+// ctorFn is the constructor signature of a native tracer.
+type ctorFn func(ctx *Context, cfg json.RawMessage, chainConfig *params.ChainConfig) (*Tracer, error)
+
+type elem struct {
+	ctor ctorFn
+	isJS bool
+}
+
+// directory is the collection of tracers bundled by default.
+type directory struct {
+	elems map[string]elem
+}
+
+// Register registers a method as a lookup for tracers, meaning users can
+// invoke a named tracer through that lookup.
+func (d *directory) Register(name string, f ctorFn, isJS bool) {
+	d.elems[name] = elem{ctor: f, isJS: isJS}
+}
+
+// Lookup reports whether the given name is registered.
+func (d *directory) Lookup(name string) bool {
+	_, ok := d.elems[name]
+	return ok
+}
+
+// New returns a new instance of the tracer registered under name.
+func (d *directory) New(name string, ctx *Context, cfg json.RawMessage, chainConfig *params.ChainConfig) (*Tracer, error) {
+	e, ok := d.elems[name]
+	if !ok {
+		return nil, errors.New("no tracer named " + name)
+	}
+	return e.ctor(ctx, cfg, chainConfig)
+}
+
+// IsJS reports whether the tracer registered under name is a JS tracer.
+func (d *directory) IsJS(name string) bool {
+	e, ok := d.elems[name]
+	return ok && e.isJS
+}
+
+// DefaultDirectory is the collection of tracers invoked on demand, e.g. via
+// debug_traceTransaction.
+var DefaultDirectory = directory{elems: make(map[string]elem)}